@@ -0,0 +1,243 @@
+package git
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	gitHTTP "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"go.flipt.io/flipt/internal/containers"
+	"go.uber.org/zap"
+)
+
+// FileCredentialProvider is a CredentialProvider that sources its TLS and
+// basic-auth material from files on disk, and watches the parent directory
+// of each configured file with fsnotify so that updates are picked up and
+// swapped in atomically without requiring Flipt to restart. Directories,
+// rather than the files themselves, are watched because Kubernetes
+// Secret/ConfigMap volumes rotate their contents by atomically re-pointing
+// a `..data` symlink, which fires a remove/rename event against the file's
+// original watch descriptor and silently stops delivering further updates.
+// Any combination of the CA bundle, client certificate and basic-auth
+// password may be configured; unconfigured material is simply omitted from
+// the returned auth method / TLS config.
+type FileCredentialProvider struct {
+	logger *zap.Logger
+
+	username     string
+	passwordFile string
+	caBundleFile string
+	certFile     string
+	keyFile      string
+
+	mu        sync.RWMutex
+	auth      transport.AuthMethod
+	tlsConfig *tls.Config
+
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+}
+
+// WithFileBasicAuth configures HTTP basic-auth credentials, re-reading the
+// password from passwordFile whenever it changes on disk.
+func WithFileBasicAuth(username, passwordFile string) containers.Option[FileCredentialProvider] {
+	return func(p *FileCredentialProvider) {
+		p.username = username
+		p.passwordFile = passwordFile
+	}
+}
+
+// WithFileCABundle configures a PEM encoded CA bundle, re-read from path
+// whenever it changes on disk.
+func WithFileCABundle(path string) containers.Option[FileCredentialProvider] {
+	return func(p *FileCredentialProvider) {
+		p.caBundleFile = path
+	}
+}
+
+// WithFileClientCertificate configures a PEM encoded client certificate and
+// key pair, re-read from certPath/keyPath whenever either changes on disk.
+func WithFileClientCertificate(certPath, keyPath string) containers.Option[FileCredentialProvider] {
+	return func(p *FileCredentialProvider) {
+		p.certFile = certPath
+		p.keyFile = keyPath
+	}
+}
+
+// NewFileCredentialProvider constructs a FileCredentialProvider, performs an
+// initial load of all configured files, and starts watching them for
+// changes. Callers must call Close when finished to stop the watcher.
+func NewFileCredentialProvider(logger *zap.Logger, opts ...containers.Option[FileCredentialProvider]) (*FileCredentialProvider, error) {
+	p := &FileCredentialProvider{
+		logger: logger,
+		done:   make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if err := p.reload(); err != nil {
+		return nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("git: starting credential file watcher: %w", err)
+	}
+	p.watcher = watcher
+
+	for _, dir := range p.watchedDirs() {
+		if err := watcher.Add(dir); err != nil {
+			_ = watcher.Close()
+			return nil, fmt.Errorf("git: watching %q: %w", dir, err)
+		}
+	}
+
+	go p.watch()
+
+	return p, nil
+}
+
+func (p *FileCredentialProvider) watchedFiles() []string {
+	var files []string
+	for _, f := range []string{p.passwordFile, p.caBundleFile, p.certFile, p.keyFile} {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// watchedDirs returns the distinct parent directories of the configured
+// credential files, so that fsnotify.Watcher is installed on each directory
+// rather than the files themselves.
+func (p *FileCredentialProvider) watchedDirs() []string {
+	seen := make(map[string]struct{})
+	var dirs []string
+	for _, f := range p.watchedFiles() {
+		dir := filepath.Dir(f)
+		if _, ok := seen[dir]; ok {
+			continue
+		}
+		seen[dir] = struct{}{}
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+func (p *FileCredentialProvider) watch() {
+	for {
+		select {
+		case <-p.done:
+			return
+		case _, ok := <-p.watcher.Events:
+			if !ok {
+				return
+			}
+			// Rather than filtering on the specific file/event, re-read all
+			// configured files whenever anything changes in their directory:
+			// a `..data` symlink swap never touches the watched file's own
+			// descriptor, so the only reliable signal is "something in this
+			// directory changed".
+			if err := p.reload(); err != nil {
+				p.logger.Error("reloading git credentials", zap.Error(err))
+				continue
+			}
+			p.logger.Info("reloaded git credentials")
+		case err, ok := <-p.watcher.Errors:
+			if !ok {
+				return
+			}
+			p.logger.Error("watching git credential files", zap.Error(err))
+		}
+	}
+}
+
+// reload re-reads all configured files and swaps the resulting auth method
+// and TLS config in atomically. A failed reload leaves the previous,
+// already-validated material in place.
+func (p *FileCredentialProvider) reload() error {
+	var (
+		auth      transport.AuthMethod
+		tlsConfig *tls.Config
+	)
+
+	if p.passwordFile != "" {
+		password, err := os.ReadFile(p.passwordFile)
+		if err != nil {
+			return fmt.Errorf("reading password file %q: %w", p.passwordFile, err)
+		}
+
+		auth = &gitHTTP.BasicAuth{Username: p.username, Password: strings.TrimSpace(string(password))}
+	}
+
+	if p.caBundleFile != "" || p.certFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+
+		tlsConfig = &tls.Config{RootCAs: pool}
+
+		if p.caBundleFile != "" {
+			bundle, err := os.ReadFile(p.caBundleFile)
+			if err != nil {
+				return fmt.Errorf("reading CA bundle file %q: %w", p.caBundleFile, err)
+			}
+
+			if ok := pool.AppendCertsFromPEM(bundle); !ok {
+				return errors.New("git: failed to parse CA bundle")
+			}
+		}
+
+		if p.certFile != "" {
+			cert, err := tls.LoadX509KeyPair(p.certFile, p.keyFile)
+			if err != nil {
+				return fmt.Errorf("loading client certificate: %w", err)
+			}
+
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+	}
+
+	p.mu.Lock()
+	p.auth = auth
+	p.tlsConfig = tlsConfig
+	p.mu.Unlock()
+
+	return nil
+}
+
+// GetAuth implements CredentialProvider.
+func (p *FileCredentialProvider) GetAuth(context.Context) (transport.AuthMethod, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.auth, nil
+}
+
+// GetTLSConfig implements CredentialProvider.
+func (p *FileCredentialProvider) GetTLSConfig(context.Context) (*tls.Config, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.tlsConfig, nil
+}
+
+// Close stops the file watcher goroutine.
+func (p *FileCredentialProvider) Close() error {
+	close(p.done)
+	if p.watcher == nil {
+		return nil
+	}
+	return p.watcher.Close()
+}
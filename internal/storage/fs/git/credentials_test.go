@@ -0,0 +1,40 @@
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gitHTTP "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func Test_FileCredentialProvider_ReloadsOnChange(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	passwordFile := filepath.Join(dir, "password")
+
+	require.NoError(t, os.WriteFile(passwordFile, []byte("initial"), 0o600))
+
+	provider, err := NewFileCredentialProvider(zaptest.NewLogger(t),
+		WithFileBasicAuth("root", passwordFile),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = provider.Close()
+	})
+
+	auth, err := provider.GetAuth(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "initial", auth.(*gitHTTP.BasicAuth).Password)
+
+	require.NoError(t, os.WriteFile(passwordFile, []byte("rotated"), 0o600))
+
+	require.Eventually(t, func() bool {
+		auth, err := provider.GetAuth(ctx)
+		return err == nil && auth.(*gitHTTP.BasicAuth).Password == "rotated"
+	}, 5*time.Second, 10*time.Millisecond, "expected rotated password to be picked up")
+}
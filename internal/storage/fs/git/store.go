@@ -0,0 +1,478 @@
+package git
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	gitHTTP "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"go.flipt.io/flipt/internal/containers"
+	"go.flipt.io/flipt/internal/storage"
+	"go.flipt.io/flipt/internal/storage/fs"
+	"go.uber.org/zap"
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SnapshotStore implements storage.fs.SnapshotStore backed by a git repository.
+// It clones the target repository, builds a snapshot from the configured ref,
+// and (optionally) polls the remote for new commits, rebuilding the snapshot
+// each time the ref is updated.
+type SnapshotStore struct {
+	*fs.Poller
+
+	logger *zap.Logger
+	url    string
+	ref    string
+
+	auth        transport.AuthMethod
+	caBundle    []byte
+	insecureTLS bool
+
+	clientCert    *tls.Certificate
+	clientCertErr error
+
+	sshAuth                  *ssh.HostKeyCallbackHelper
+	sshHostKeyCallback       gossh.HostKeyCallback
+	sshKnownHostsFileSet     bool
+	sshInsecureIgnoreHostKey bool
+	sshErr                   error
+
+	// credentials supplies the auth and TLS material used for each fetch.
+	// When unset, NewSnapshotStore wraps the static auth/TLS options above
+	// in a staticCredentialProvider so behaviour is unchanged for callers who don't
+	// need hot-reloadable credentials.
+	credentials CredentialProvider
+
+	verifier *commitVerifier
+
+	pollOpts []containers.Option[fs.Poller]
+
+	mu   sync.Mutex
+	repo *git.Repository
+}
+
+// String returns an identifier for this store.
+func (s *SnapshotStore) String() string {
+	return "git"
+}
+
+// WithRef configures the target reference used to build snapshots: a branch
+// name or a full commit hash. Any other value, a tag name included, is
+// looked up as a branch. Defaults to "main" when not supplied.
+func WithRef(ref string) containers.Option[SnapshotStore] {
+	return func(s *SnapshotStore) {
+		s.ref = ref
+	}
+}
+
+// WithAuth configures the transport.AuthMethod used to authenticate against
+// an HTTPS remote (e.g. http.BasicAuth or http.TokenAuth).
+func WithAuth(auth transport.AuthMethod) containers.Option[SnapshotStore] {
+	return func(s *SnapshotStore) {
+		s.auth = auth
+	}
+}
+
+// WithPollOptions configures the options passed to the underlying fs.Poller
+// used to periodically fetch and rebuild snapshots. This is optional: a
+// poll interval of 0 disables the background loop entirely, in which case
+// the store relies solely on explicit calls to Refresh, e.g. from a
+// WebhookHandler.
+func WithPollOptions(opts ...containers.Option[fs.Poller]) containers.Option[SnapshotStore] {
+	return func(s *SnapshotStore) {
+		s.pollOpts = opts
+	}
+}
+
+// WithInsecureTLS disables TLS certificate verification for HTTPS remotes.
+// This should only be used for testing.
+func WithInsecureTLS(insecure bool) containers.Option[SnapshotStore] {
+	return func(s *SnapshotStore) {
+		s.insecureTLS = insecure
+	}
+}
+
+// WithCABundle configures a PEM encoded certificate bundle used to verify
+// the certificate presented by an HTTPS remote (e.g. for self-signed or
+// privately issued certificates).
+func WithCABundle(bundle []byte) containers.Option[SnapshotStore] {
+	return func(s *SnapshotStore) {
+		s.caBundle = bundle
+	}
+}
+
+// WithClientCertificate configures a PEM encoded client certificate and
+// private key presented to HTTPS remotes that require mutual TLS
+// authentication, analogous to `--cert-file`/`--key-file` for a Helm chart
+// repository.
+func WithClientCertificate(certPEM, keyPEM []byte) containers.Option[SnapshotStore] {
+	return func(s *SnapshotStore) {
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			s.clientCertErr = fmt.Errorf("loading client certificate: %w", err)
+			return
+		}
+
+		s.clientCert = &cert
+	}
+}
+
+// WithClientCertificateFiles is like WithClientCertificate but reads the
+// certificate and key from the files found at certPath and keyPath.
+func WithClientCertificateFiles(certPath, keyPath string) containers.Option[SnapshotStore] {
+	return func(s *SnapshotStore) {
+		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err != nil {
+			s.clientCertErr = fmt.Errorf("loading client certificate: %w", err)
+			return
+		}
+
+		s.clientCert = &cert
+	}
+}
+
+// CredentialProvider supplies the auth method and TLS configuration used to
+// fetch from the remote. Unlike the static With* options above, which are
+// captured once at construction time, a CredentialProvider is consulted on
+// every fetch, so an implementation backed by files on disk (see
+// FileCredentialProvider) can rotate a CA bundle, client certificate or
+// HTTP credential without requiring Flipt to restart.
+type CredentialProvider interface {
+	GetAuth(ctx context.Context) (transport.AuthMethod, error)
+	GetTLSConfig(ctx context.Context) (*tls.Config, error)
+}
+
+// WithCredentialProvider overrides the credential provider used on each
+// fetch. This takes precedence over WithAuth, WithCABundle, WithInsecureTLS
+// and WithClientCertificate(Files), which otherwise configure an internal
+// provider that returns the same static material every time.
+func WithCredentialProvider(provider CredentialProvider) containers.Option[SnapshotStore] {
+	return func(s *SnapshotStore) {
+		s.credentials = provider
+	}
+}
+
+// staticCredentialProvider returns the same auth method and TLS config on
+// every call. It backs a SnapshotStore constructed from the static With*
+// options when no CredentialProvider has been supplied explicitly.
+type staticCredentialProvider struct {
+	auth      transport.AuthMethod
+	tlsConfig *tls.Config
+}
+
+func (p *staticCredentialProvider) GetAuth(context.Context) (transport.AuthMethod, error) {
+	return p.auth, nil
+}
+
+func (p *staticCredentialProvider) GetTLSConfig(context.Context) (*tls.Config, error) {
+	return p.tlsConfig, nil
+}
+
+// WithSSHKeyFile configures key-based SSH authentication using the private
+// key found at path. passphrase may be empty if the key is not encrypted.
+func WithSSHKeyFile(path string, passphrase string) containers.Option[SnapshotStore] {
+	return func(s *SnapshotStore) {
+		auth, err := ssh.NewPublicKeysFromFile("git", path, passphrase)
+		if err != nil {
+			s.sshErr = fmt.Errorf("loading ssh key file %q: %w", path, err)
+			return
+		}
+
+		s.auth = auth
+		s.sshAuth = &auth.HostKeyCallbackHelper
+	}
+}
+
+// WithSSHKeyBytes configures key-based SSH authentication using an in-memory
+// PEM encoded private key. passphrase may be empty if the key is not
+// encrypted.
+func WithSSHKeyBytes(pem []byte, passphrase string) containers.Option[SnapshotStore] {
+	return func(s *SnapshotStore) {
+		auth, err := ssh.NewPublicKeys("git", pem, passphrase)
+		if err != nil {
+			s.sshErr = fmt.Errorf("loading ssh key: %w", err)
+			return
+		}
+
+		s.auth = auth
+		s.sshAuth = &auth.HostKeyCallbackHelper
+	}
+}
+
+// WithSSHAgent configures SSH authentication via the local ssh-agent,
+// reached through the SSH_AUTH_SOCK environment variable.
+func WithSSHAgent() containers.Option[SnapshotStore] {
+	return func(s *SnapshotStore) {
+		auth, err := ssh.NewSSHAgentAuth("git")
+		if err != nil {
+			s.sshErr = fmt.Errorf("connecting to ssh agent: %w", err)
+			return
+		}
+
+		s.auth = auth
+		s.sshAuth = &auth.HostKeyCallbackHelper
+	}
+}
+
+// WithSSHKnownHostsFile configures host key verification against the
+// known_hosts file found at path. It conflicts with
+// WithSSHInsecureIgnoreHostKey.
+func WithSSHKnownHostsFile(path string) containers.Option[SnapshotStore] {
+	return func(s *SnapshotStore) {
+		callback, err := knownhosts.New(path)
+		if err != nil {
+			s.sshErr = fmt.Errorf("loading known_hosts file %q: %w", path, err)
+			return
+		}
+
+		s.sshHostKeyCallback = callback
+		s.sshKnownHostsFileSet = true
+	}
+}
+
+// WithSSHInsecureIgnoreHostKey disables SSH host key verification entirely.
+// This should only be used for testing. It conflicts with
+// WithSSHKnownHostsFile.
+func WithSSHInsecureIgnoreHostKey() containers.Option[SnapshotStore] {
+	return func(s *SnapshotStore) {
+		s.sshHostKeyCallback = ssh.InsecureIgnoreHostKey()
+		s.sshInsecureIgnoreHostKey = true
+	}
+}
+
+// isSSHURL reports whether url looks like a git SSH remote, either in scp-like
+// form (e.g. git@host:owner/repo.git) or as an explicit ssh:// URL.
+func isSSHURL(url string) bool {
+	return strings.HasPrefix(url, "ssh://") ||
+		(strings.Contains(url, "@") && strings.Contains(url, ":") && !strings.Contains(url, "://"))
+}
+
+// NewSnapshotStore constructs a SnapshotStore which clones and builds
+// snapshots from the git repository found at url.
+func NewSnapshotStore(ctx context.Context, logger *zap.Logger, url string, opts ...containers.Option[SnapshotStore]) (*SnapshotStore, error) {
+	store := &SnapshotStore{
+		logger: logger,
+		url:    url,
+		ref:    "main",
+	}
+
+	for _, opt := range opts {
+		opt(store)
+	}
+
+	if store.sshErr != nil {
+		return nil, store.sshErr
+	}
+
+	if store.clientCertErr != nil {
+		return nil, store.clientCertErr
+	}
+
+	if store.sshKnownHostsFileSet && store.sshInsecureIgnoreHostKey {
+		return nil, errors.New("git: WithSSHKnownHostsFile and WithSSHInsecureIgnoreHostKey are mutually exclusive")
+	}
+
+	if isSSHURL(url) {
+		if store.sshAuth == nil {
+			return nil, errors.New("git: ssh remote requires one of WithSSHKeyFile, WithSSHKeyBytes or WithSSHAgent")
+		}
+
+		if store.caBundle != nil || store.insecureTLS || store.clientCert != nil {
+			return nil, errors.New("git: TLS options are not valid for ssh remotes")
+		}
+
+		if store.sshHostKeyCallback != nil {
+			store.sshAuth.HostKeyCallback = store.sshHostKeyCallback
+		}
+	} else {
+		if store.sshAuth != nil || store.sshHostKeyCallback != nil {
+			return nil, errors.New("git: SSH auth options are not valid for non-ssh remotes")
+		}
+	}
+
+	if store.credentials == nil {
+		tlsConfig, err := store.buildTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+
+		store.credentials = &staticCredentialProvider{auth: store.auth, tlsConfig: tlsConfig}
+	}
+
+	if _, err := store.fetch(ctx); err != nil {
+		return nil, err
+	}
+
+	poller := fs.NewPoller(logger, store.fetch, store.pollOpts...)
+	store.Poller = poller
+
+	return store, nil
+}
+
+// buildTLSConfig builds the *tls.Config implied by the static
+// WithCABundle/WithInsecureTLS/WithClientCertificate(Files) options, or nil
+// if none of them were set.
+func (s *SnapshotStore) buildTLSConfig() (*tls.Config, error) {
+	if s.caBundle == nil && !s.insecureTLS && s.clientCert == nil {
+		return nil, nil
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+
+	if s.caBundle != nil {
+		if ok := pool.AppendCertsFromPEM(s.caBundle); !ok {
+			return nil, errors.New("git: failed to parse CA bundle")
+		}
+	}
+
+	tlsConfig := &tls.Config{
+		RootCAs:            pool,
+		InsecureSkipVerify: s.insecureTLS,
+	}
+
+	if s.clientCert != nil {
+		tlsConfig.Certificates = []tls.Certificate{*s.clientCert}
+	}
+
+	return tlsConfig, nil
+}
+
+// gitTransportMu serializes installation of go-git's process-wide HTTPS
+// client. go-git has no per-clone way to set TLS options (client
+// certificates in particular aren't supported by CloneOptions'
+// InsecureSkipTLS/CABundle fields), only a global client installed via
+// client.InstallProtocol, so cloneWithTLS holds this lock for the duration
+// of the clone to stop concurrent stores with different TLS material from
+// clobbering each other's transport mid-fetch.
+var gitTransportMu sync.Mutex
+
+// cloneWithTLS clones the repository, installing tlsConfig as go-git's
+// process-wide HTTPS client for the duration of the clone (or the library
+// default if tlsConfig is nil), and always restoring the default client
+// once the clone completes so no store's TLS material lingers as global
+// state beyond its own fetch.
+func (s *SnapshotStore) cloneWithTLS(ctx context.Context, auth transport.AuthMethod, tlsConfig *tls.Config) (*git.Repository, error) {
+	gitTransportMu.Lock()
+	defer gitTransportMu.Unlock()
+
+	httpsClient := gitHTTP.DefaultClient
+	if tlsConfig != nil {
+		httpsClient = gitHTTP.NewClient(&http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: tlsConfig,
+			},
+		})
+	}
+
+	client.InstallProtocol("https", httpsClient)
+	defer client.InstallProtocol("https", gitHTTP.DefaultClient)
+
+	if plumbing.IsHash(s.ref) {
+		repo, err := git.CloneContext(ctx, memory.NewStorage(), memfs.New(), &git.CloneOptions{URL: s.url, Auth: auth})
+		if err != nil {
+			return nil, err
+		}
+		tree, err := repo.Worktree()
+		if err != nil {
+			return nil, err
+		}
+		return repo, tree.Checkout(&git.CheckoutOptions{Hash: plumbing.NewHash(s.ref)})
+	}
+
+	return git.CloneContext(ctx, memory.NewStorage(), memfs.New(), &git.CloneOptions{
+		URL:           s.url,
+		Auth:          auth,
+		ReferenceName: plumbing.NewBranchReferenceName(s.ref),
+		SingleBranch:  true,
+		Depth:         1,
+	})
+}
+
+// fetch clones (or re-clones) the repository and builds a new snapshot,
+// swapping it in atomically once it has been built successfully. The auth
+// method and TLS config are pulled from s.credentials on every call, so a
+// CredentialProvider that rotates its underlying material takes effect on
+// the very next fetch without requiring a restart.
+func (s *SnapshotStore) fetch(ctx context.Context) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	auth, err := s.credentials.GetAuth(ctx)
+	if err != nil {
+		return false, fmt.Errorf("git: getting auth credentials: %w", err)
+	}
+
+	tlsConfig, err := s.credentials.GetTLSConfig(ctx)
+	if err != nil {
+		return false, fmt.Errorf("git: getting tls config: %w", err)
+	}
+
+	repo, err := s.cloneWithTLS(ctx, auth, tlsConfig)
+	if err != nil {
+		return false, fmt.Errorf("git: cloning %q: %w", s.url, err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return false, fmt.Errorf("git: resolving HEAD: %w", err)
+	}
+
+	if s.repo != nil {
+		if current, err := s.repo.Head(); err == nil && current.Hash() == head.Hash() {
+			return false, nil
+		}
+	}
+
+	if s.verifier != nil {
+		commit, err := repo.CommitObject(head.Hash())
+		if err != nil {
+			return false, fmt.Errorf("git: resolving commit %s: %w", head.Hash(), err)
+		}
+
+		if err := s.verifier.Verify(ctx, commit); err != nil {
+			snapshotVerificationFailedTotal.Inc()
+			s.logger.Warn("rejecting snapshot: commit signature verification failed",
+				zap.String("commit", head.Hash().String()),
+				zap.Error(err),
+			)
+			return false, nil
+		}
+	}
+
+	s.repo = repo
+
+	return true, nil
+}
+
+// View exposes the most recently built snapshot to fn.
+func (s *SnapshotStore) View(fn func(storage.ReadOnlyStore) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.Poller.View(fn)
+}
+
+// Refresh synchronously fetches the configured ref and rebuilds the
+// snapshot if the remote has moved on. It is exported so that callers
+// outside of the poll loop (e.g. a webhook handler) can trigger an
+// on-demand update.
+func (s *SnapshotStore) Refresh(ctx context.Context) error {
+	_, err := s.fetch(ctx)
+	return err
+}
@@ -3,12 +3,27 @@ package git
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
 	"encoding/pem"
+	"fmt"
+	"math/big"
 	"net/http/httptest"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
 	"github.com/go-git/go-billy/v5/memfs"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
@@ -120,6 +135,186 @@ flags:
 	}))
 }
 
+func Test_WebhookHandler_Push(t *testing.T) {
+	// poll interval of 0 disables the background loop entirely: the
+	// snapshot is only ever refreshed in response to an inbound webhook.
+	store, skip := testStore(t, WithPollOptions(fs.WithInterval(0)))
+	if skip {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	secret := []byte("s3cr3t")
+	handler := NewWebhookHandler(store, WithWebhookSecret(secret))
+
+	// pull repo
+	workdir := memfs.New()
+	repo, err := git.Clone(memory.NewStorage(), workdir, &git.CloneOptions{
+		Auth:          &http.BasicAuth{Username: "root", Password: "password"},
+		URL:           gitRepoURL,
+		RemoteName:    "origin",
+		ReferenceName: plumbing.NewBranchReferenceName("main"),
+	})
+	require.NoError(t, err)
+
+	tree, err := repo.Worktree()
+	require.NoError(t, err)
+
+	require.NoError(t, tree.Checkout(&git.CheckoutOptions{
+		Branch: "refs/heads/main",
+	}))
+
+	fi, err := workdir.OpenFile("features.yml", os.O_TRUNC|os.O_RDWR, os.ModePerm)
+	require.NoError(t, err)
+
+	updated := []byte(`namespace: production
+flags:
+    - key: bar
+      name: Bar`)
+
+	_, err = fi.Write(updated)
+	require.NoError(t, err)
+	require.NoError(t, fi.Close())
+
+	_, err = tree.Commit("chore: update features.yml", &git.CommitOptions{
+		All:    true,
+		Author: &object.Signature{Email: "dev@flipt.io", Name: "dev"},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Push(&git.PushOptions{
+		Auth:       &http.BasicAuth{Username: "root", Password: "password"},
+		RemoteName: "origin",
+	}))
+
+	payload, err := json.Marshal(map[string]any{"ref": "refs/heads/main"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		signature      string
+		wantStatusCode int
+	}{
+		{
+			name:           "invalid signature",
+			signature:      "sha256=deadbeef",
+			wantStatusCode: 401,
+		},
+		{
+			name:           "valid signature",
+			signature:      githubSignature(secret, payload),
+			wantStatusCode: 200,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/internal/git/webhook", bytes.NewReader(payload))
+			req.Header.Set("X-GitHub-Event", "push")
+			req.Header.Set("X-Hub-Signature-256", tt.signature)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			require.Equal(t, tt.wantStatusCode, rec.Code)
+		})
+	}
+
+	require.NoError(t, store.View(func(s storage.ReadOnlyStore) error {
+		_, err = s.GetFlag(ctx, "production", "bar")
+		return err
+	}))
+}
+
+func githubSignature(secret, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func Test_WebhookHandler_ParsePush_Providers(t *testing.T) {
+	secret := []byte("s3cr3t")
+	store := &SnapshotStore{logger: zaptest.NewLogger(t), ref: "main"}
+	handler := NewWebhookHandler(store, WithWebhookSecret(secret))
+
+	pushPayload, err := json.Marshal(map[string]any{"ref": "refs/heads/main"})
+	require.NoError(t, err)
+
+	bitbucketPayload, err := json.Marshal(map[string]any{
+		"push": map[string]any{
+			"changes": []map[string]any{
+				{"new": map[string]any{"type": "branch", "name": "main"}},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	hmacHex := func(body []byte) string {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(body)
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		body    []byte
+		wantErr bool
+	}{
+		{
+			name:    "gitlab valid token",
+			headers: map[string]string{"X-Gitlab-Event": "Push Hook", "X-Gitlab-Token": string(secret)},
+			body:    pushPayload,
+		},
+		{
+			name:    "gitlab invalid token is rejected",
+			headers: map[string]string{"X-Gitlab-Event": "Push Hook", "X-Gitlab-Token": "wrong"},
+			body:    pushPayload,
+			wantErr: true,
+		},
+		{
+			name:    "gitea valid signature",
+			headers: map[string]string{"X-Gitea-Event": "push", "X-Gitea-Signature": hmacHex(pushPayload)},
+			body:    pushPayload,
+		},
+		{
+			name:    "gitea missing signature is rejected",
+			headers: map[string]string{"X-Gitea-Event": "push"},
+			body:    pushPayload,
+			wantErr: true,
+		},
+		{
+			name:    "bitbucket valid signature",
+			headers: map[string]string{"X-Event-Key": "repo:push", "X-Hub-Signature": "sha256=" + hmacHex(bitbucketPayload)},
+			body:    bitbucketPayload,
+		},
+		{
+			name:    "bitbucket missing signature is rejected",
+			headers: map[string]string{"X-Event-Key": "repo:push"},
+			body:    bitbucketPayload,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/internal/git/webhook", bytes.NewReader(tt.body))
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			ref, _, err := handler.parsePush(req, tt.body)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "refs/heads/main", ref)
+		})
+	}
+}
+
 func Test_Store_SelfSignedSkipTLS(t *testing.T) {
 	ts := httptest.NewTLSServer(nil)
 	defer ts.Close()
@@ -156,6 +351,350 @@ func Test_Store_SelfSignedCABytes(t *testing.T) {
 	require.ErrorIs(t, err, transport.ErrRepositoryNotFound)
 }
 
+func Test_Store_ClientCertificate(t *testing.T) {
+	certPEM, keyPEM, clientCert := generateSelfSignedCert(t, "flipt-client")
+
+	pool := x509.NewCertPool()
+	pool.AddCert(clientCert)
+
+	ts := httptest.NewUnstartedServer(nil)
+	ts.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  pool,
+	}
+	ts.StartTLS()
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	require.NoError(t, pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}))
+
+	gitRepoURL = ts.URL
+
+	err := testStoreWithError(t, WithCABundle(buf.Bytes()))
+	require.ErrorContains(t, err, "tls: ")
+
+	err = testStoreWithError(t, WithCABundle(buf.Bytes()), WithClientCertificate(certPEM, keyPEM))
+	// This time, TLS handshake succeeds and we fail for an unrelated reason
+	require.ErrorIs(t, err, transport.ErrRepositoryNotFound)
+}
+
+func generateSelfSignedCert(t *testing.T, commonName string) (certPEM, keyPEM []byte, cert *x509.Certificate) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	var certBuf, keyBuf bytes.Buffer
+	require.NoError(t, pem.Encode(&certBuf, &pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	require.NoError(t, pem.Encode(&keyBuf, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+
+	cert, err = x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return certBuf.Bytes(), keyBuf.Bytes(), cert
+}
+
+func Test_Store_CommitVerification(t *testing.T) {
+	entity, armoredPub := generateGPGKey(t)
+
+	modified := make(chan struct{}, 1)
+	store, skip := testStore(t,
+		WithCommitVerification([]byte(armoredPub), nil),
+		WithPollOptions(fs.WithInterval(time.Second), fs.WithNotify(t, func(ok bool) {
+			if ok {
+				select {
+				case modified <- struct{}{}:
+				default:
+				}
+			}
+		})),
+	)
+	if skip {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	workdir := memfs.New()
+	repo, err := git.Clone(memory.NewStorage(), workdir, &git.CloneOptions{
+		Auth:          &http.BasicAuth{Username: "root", Password: "password"},
+		URL:           gitRepoURL,
+		RemoteName:    "origin",
+		ReferenceName: plumbing.NewBranchReferenceName("main"),
+	})
+	require.NoError(t, err)
+
+	tree, err := repo.Worktree()
+	require.NoError(t, err)
+	require.NoError(t, tree.Checkout(&git.CheckoutOptions{Branch: "refs/heads/main"}))
+
+	writeFeatures := func(key string) {
+		fi, err := workdir.OpenFile("features.yml", os.O_TRUNC|os.O_RDWR, os.ModePerm)
+		require.NoError(t, err)
+		_, err = fi.Write([]byte(fmt.Sprintf("namespace: production\nflags:\n    - key: %s\n      name: %s", key, key)))
+		require.NoError(t, err)
+		require.NoError(t, fi.Close())
+	}
+
+	// push a signed commit: this one should be promoted to a new snapshot
+	writeFeatures("signed")
+	_, err = tree.Commit("chore: signed update", &git.CommitOptions{
+		All:     true,
+		Author:  &object.Signature{Email: "dev@flipt.io", Name: "dev"},
+		SignKey: entity,
+	})
+	require.NoError(t, err)
+	require.NoError(t, repo.Push(&git.PushOptions{Auth: &http.BasicAuth{Username: "root", Password: "password"}, RemoteName: "origin"}))
+
+	select {
+	case <-modified:
+	case <-time.After(time.Minute):
+		t.Fatal("timed out waiting for signed snapshot")
+	}
+
+	require.NoError(t, store.View(func(s storage.ReadOnlyStore) error {
+		_, err := s.GetFlag(ctx, "production", "signed")
+		return err
+	}))
+
+	// push an unsigned commit: it must be rejected and the signed snapshot
+	// above must keep being served
+	writeFeatures("unsigned")
+	_, err = tree.Commit("chore: unsigned update", &git.CommitOptions{
+		All:    true,
+		Author: &object.Signature{Email: "dev@flipt.io", Name: "dev"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, repo.Push(&git.PushOptions{Auth: &http.BasicAuth{Username: "root", Password: "password"}, RemoteName: "origin"}))
+
+	require.NoError(t, store.Refresh(ctx))
+
+	require.NoError(t, store.View(func(s storage.ReadOnlyStore) error {
+		_, err := s.GetFlag(ctx, "production", "signed")
+		return err
+	}))
+
+	require.Error(t, store.View(func(s storage.ReadOnlyStore) error {
+		_, err := s.GetFlag(ctx, "production", "unsigned")
+		return err
+	}))
+}
+
+func Test_Store_CommitVerification_SSH(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not found on PATH, skipping SSH signed commit test")
+		return
+	}
+
+	const signerEmail = "dev@flipt.io"
+
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "id_ed25519")
+	require.NoError(t, exec.Command("ssh-keygen", "-t", "ed25519", "-f", keyPath, "-N", "", "-C", signerEmail).Run())
+
+	pubKey, err := os.ReadFile(keyPath + ".pub")
+	require.NoError(t, err)
+
+	authorizedSigners := []byte(signerEmail + " " + string(pubKey))
+
+	store, skip := testStore(t,
+		WithCommitVerification(nil, authorizedSigners),
+		WithPollOptions(fs.WithInterval(0)),
+	)
+	if skip {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	workdir := memfs.New()
+	repo, err := git.Clone(memory.NewStorage(), workdir, &git.CloneOptions{
+		Auth:          &http.BasicAuth{Username: "root", Password: "password"},
+		URL:           gitRepoURL,
+		RemoteName:    "origin",
+		ReferenceName: plumbing.NewBranchReferenceName("main"),
+	})
+	require.NoError(t, err)
+
+	tree, err := repo.Worktree()
+	require.NoError(t, err)
+	require.NoError(t, tree.Checkout(&git.CheckoutOptions{Branch: "refs/heads/main"}))
+
+	fi, err := workdir.OpenFile("features.yml", os.O_TRUNC|os.O_RDWR, os.ModePerm)
+	require.NoError(t, err)
+	_, err = fi.Write([]byte("namespace: production\nflags:\n    - key: sshsigned\n      name: sshsigned"))
+	require.NoError(t, err)
+	require.NoError(t, fi.Close())
+
+	hash, err := tree.Commit("chore: ssh signed update", &git.CommitOptions{
+		All:    true,
+		Author: &object.Signature{Email: signerEmail, Name: "dev"},
+	})
+	require.NoError(t, err)
+
+	commit, err := repo.CommitObject(hash)
+	require.NoError(t, err)
+
+	payload, err := commitSignaturePayload(commit)
+	require.NoError(t, err)
+
+	messagePath := filepath.Join(dir, "message")
+	require.NoError(t, os.WriteFile(messagePath, payload, 0o600))
+	require.NoError(t, exec.Command("ssh-keygen", "-Y", "sign", "-f", keyPath, "-n", "git", messagePath).Run())
+
+	signature, err := os.ReadFile(messagePath + ".sig")
+	require.NoError(t, err)
+
+	signed := *commit
+	signed.PGPSignature = string(signature)
+
+	obj := repo.Storer.NewEncodedObject()
+	obj.SetType(plumbing.CommitObject)
+	require.NoError(t, signed.Encode(obj))
+
+	newHash, err := repo.Storer.SetEncodedObject(obj)
+	require.NoError(t, err)
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("main"), newHash)))
+
+	require.NoError(t, repo.Push(&git.PushOptions{
+		Auth:       &http.BasicAuth{Username: "root", Password: "password"},
+		RemoteName: "origin",
+	}))
+
+	require.NoError(t, store.Refresh(ctx))
+
+	require.NoError(t, store.View(func(s storage.ReadOnlyStore) error {
+		_, err := s.GetFlag(ctx, "production", "sshsigned")
+		return err
+	}))
+}
+
+func generateGPGKey(t *testing.T) (*openpgp.Entity, string) {
+	t.Helper()
+
+	entity, err := openpgp.NewEntity("flipt test", "", "flipt-test@flipt.io", nil)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.Serialize(w))
+	require.NoError(t, w.Close())
+
+	return entity, buf.String()
+}
+
+func Test_isSSHURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"git@github.com:flipt-io/flipt.git", true},
+		{"ssh://git@github.com/flipt-io/flipt.git", true},
+		{"https://github.com/flipt-io/flipt.git", false},
+		{"http://localhost:8080/repo.git", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			require.Equal(t, tt.want, isSSHURL(tt.url))
+		})
+	}
+}
+
+func Test_Store_SSHRequiresAuth(t *testing.T) {
+	_, err := NewSnapshotStore(context.Background(), zaptest.NewLogger(t), "git@github.com:flipt-io/flipt.git")
+	require.ErrorContains(t, err, "ssh remote requires one of")
+}
+
+func Test_Store_SSHConflictingTLSOptions(t *testing.T) {
+	_, err := NewSnapshotStore(context.Background(), zaptest.NewLogger(t), "git@github.com:flipt-io/flipt.git",
+		WithSSHInsecureIgnoreHostKey(),
+		WithInsecureTLS(true),
+	)
+	require.ErrorContains(t, err, "ssh remote requires one of")
+
+	_, err = NewSnapshotStore(context.Background(), zaptest.NewLogger(t), "git@github.com:flipt-io/flipt.git",
+		WithSSHKeyBytes([]byte("not-a-real-key"), ""),
+	)
+	require.Error(t, err)
+
+	// with a valid ssh key supplied, the ssh-remote-requires-auth check
+	// passes, so this must fail on the actual TLS-conflict path instead.
+	_, err = NewSnapshotStore(context.Background(), zaptest.NewLogger(t), "git@github.com:flipt-io/flipt.git",
+		WithSSHKeyBytes(generateSSHKeyPEM(t), ""),
+		WithInsecureTLS(true),
+	)
+	require.ErrorContains(t, err, "TLS options are not valid for ssh remotes")
+}
+
+func Test_Store_SSHAuthOnNonSSHRemote(t *testing.T) {
+	_, err := NewSnapshotStore(context.Background(), zaptest.NewLogger(t), "https://github.com/flipt-io/flipt.git",
+		WithSSHKeyBytes(generateSSHKeyPEM(t), ""),
+	)
+	require.ErrorContains(t, err, "SSH auth options are not valid for non-ssh remotes")
+}
+
+func Test_Store_SSHConflictingHostKeyOptions(t *testing.T) {
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	require.NoError(t, os.WriteFile(knownHosts, nil, 0o600))
+
+	_, err := NewSnapshotStore(context.Background(), zaptest.NewLogger(t), "git@github.com:flipt-io/flipt.git",
+		WithSSHKeyBytes(generateSSHKeyPEM(t), ""),
+		WithSSHKnownHostsFile(knownHosts),
+		WithSSHInsecureIgnoreHostKey(),
+	)
+	require.ErrorContains(t, err, "mutually exclusive")
+}
+
+func generateSSHKeyPEM(t *testing.T) []byte {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, pem.Encode(&buf, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+
+	return buf.Bytes()
+}
+
+func Test_Store_SSH(t *testing.T) {
+	sshRepoURL := os.Getenv("TEST_GIT_SSH_REPO_URL")
+	keyFile := os.Getenv("TEST_GIT_SSH_KEY_FILE")
+	if sshRepoURL == "" || keyFile == "" {
+		t.Skip("Set non-empty TEST_GIT_SSH_REPO_URL and TEST_GIT_SSH_KEY_FILE env vars to run this test.")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	store, err := NewSnapshotStore(ctx, zaptest.NewLogger(t), sshRepoURL,
+		WithRef("main"),
+		WithSSHKeyFile(keyFile, ""),
+		WithSSHInsecureIgnoreHostKey(),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = store.Close()
+	})
+}
+
 func testStore(t *testing.T, opts ...containers.Option[SnapshotStore]) (*SnapshotStore, bool) {
 	t.Helper()
 
@@ -0,0 +1,158 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.flipt.io/flipt/internal/containers"
+)
+
+// snapshotVerificationFailedTotal counts commits that were fetched but
+// rejected by commit verification, and so did not result in a new snapshot
+// being promoted.
+var snapshotVerificationFailedTotal = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: "flipt",
+	Subsystem: "storage_fs_git",
+	Name:      "snapshot_verification_failed_total",
+	Help:      "The total number of fetched git commits rejected by commit signature verification",
+})
+
+// WithCommitVerification enables commit signature verification: before a
+// freshly fetched commit is promoted to the current snapshot, its GPG or
+// SSH signature is checked against gpgKeyRing (an armored OpenPGP keyring)
+// and/or authorizedSigners (an OpenSSH `authorized_signers` file). Either
+// argument may be nil if only one signature scheme is in use. A commit that
+// fails verification is logged and rejected; the store continues serving
+// the last snapshot that did verify.
+func WithCommitVerification(gpgKeyRing []byte, authorizedSigners []byte) containers.Option[SnapshotStore] {
+	return func(s *SnapshotStore) {
+		s.verifier = &commitVerifier{
+			gpgKeyRing:        gpgKeyRing,
+			authorizedSigners: authorizedSigners,
+		}
+	}
+}
+
+// commitVerifier checks a commit's signature against a configured GPG
+// keyring and/or SSH authorized_signers file.
+type commitVerifier struct {
+	gpgKeyRing        []byte
+	authorizedSigners []byte
+}
+
+// Verify returns an error if commit is unsigned, or its signature doesn't
+// verify against the configured keyring/authorized signers.
+func (v *commitVerifier) Verify(ctx context.Context, commit *object.Commit) error {
+	if commit.PGPSignature == "" {
+		return errors.New("commit is not signed")
+	}
+
+	if strings.Contains(commit.PGPSignature, "BEGIN SSH SIGNATURE") {
+		return v.verifySSH(ctx, commit)
+	}
+
+	if len(v.gpgKeyRing) == 0 {
+		return errors.New("commit has a PGP signature but no GPG keyring was configured")
+	}
+
+	_, err := commit.Verify(string(v.gpgKeyRing))
+	return err
+}
+
+// verifySSH shells out to `ssh-keygen -Y verify`, the same mechanism `git`
+// itself uses (via gpg.ssh.program) to verify SSH signed commits. go-git
+// has no native equivalent of `commit.VerifyWithKey` for the SSH signature
+// format (its Verify only understands OpenPGP), so this requires an
+// `ssh-keygen` binary (OpenSSH >= 8.0) to be present on PATH.
+func (v *commitVerifier) verifySSH(ctx context.Context, commit *object.Commit) error {
+	if len(v.authorizedSigners) == 0 {
+		return errors.New("commit has an SSH signature but no authorized_signers file was configured")
+	}
+
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		return fmt.Errorf("git: verifying SSH signed commits requires the ssh-keygen binary (OpenSSH >= 8.0) on PATH: %w", err)
+	}
+
+	payload, err := commitSignaturePayload(commit)
+	if err != nil {
+		return fmt.Errorf("building commit signature payload: %w", err)
+	}
+
+	signers, err := os.CreateTemp("", "flipt-git-authorized-signers-*")
+	if err != nil {
+		return fmt.Errorf("creating authorized_signers temp file: %w", err)
+	}
+	defer os.Remove(signers.Name())
+
+	if _, err := signers.Write(v.authorizedSigners); err != nil {
+		return fmt.Errorf("writing authorized_signers temp file: %w", err)
+	}
+	if err := signers.Close(); err != nil {
+		return err
+	}
+
+	sigFile, err := os.CreateTemp("", "flipt-git-commit-sig-*")
+	if err != nil {
+		return fmt.Errorf("creating signature temp file: %w", err)
+	}
+	defer os.Remove(sigFile.Name())
+
+	if _, err := sigFile.WriteString(commit.PGPSignature); err != nil {
+		return fmt.Errorf("writing signature temp file: %w", err)
+	}
+	if err := sigFile.Close(); err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, "ssh-keygen", "-Y", "verify",
+		"-f", signers.Name(),
+		"-I", commit.Committer.Email,
+		"-n", "git",
+		"-s", sigFile.Name(),
+	)
+	cmd.Stdin = bytes.NewReader(payload)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("ssh-keygen verify: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+// commitSignaturePayload reconstructs the exact bytes `git` signs for a
+// commit: the commit object encoded without its gpgsig trailer. Rather than
+// hand-rolling the commit object format (and risking missing headers like
+// `encoding` or `mergetag`), this clones the commit, clears its signature
+// and delegates to the same Encode that go-git itself uses to write commit
+// objects to storage, which omits the gpgsig header entirely when the
+// signature is empty - exactly the payload `git` signs.
+func commitSignaturePayload(commit *object.Commit) ([]byte, error) {
+	unsigned := *commit
+	unsigned.PGPSignature = ""
+
+	obj := &plumbing.MemoryObject{}
+	if err := unsigned.Encode(obj); err != nil {
+		return nil, err
+	}
+
+	r, err := obj.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
@@ -0,0 +1,256 @@
+package git
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"go.flipt.io/flipt/internal/containers"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+// WebhookHandler is an http.Handler that triggers an on-demand refresh of a
+// SnapshotStore in response to a push event from a supported git hosting
+// provider (GitHub, GitLab, Gitea or Bitbucket). It can be mounted, e.g. at
+// `/internal/git/webhook`, alongside the regular poll loop, so that
+// snapshots are updated as soon as a change is pushed rather than waiting
+// for the next poll interval, or in place of it when polling is disabled.
+type WebhookHandler struct {
+	logger *zap.Logger
+	store  *SnapshotStore
+	secret []byte
+
+	group singleflight.Group
+
+	mu    sync.Mutex
+	dirty bool
+}
+
+// WithWebhookSecret configures the shared secret used to verify the
+// authenticity of incoming webhook payloads. For providers that sign their
+// payloads (GitHub, Gitea, Bitbucket) this is used to validate the request's HMAC
+// signature. For GitLab, which instead sends the secret verbatim, it is
+// compared directly against the `X-Gitlab-Token` header.
+func WithWebhookSecret(secret []byte) containers.Option[WebhookHandler] {
+	return func(h *WebhookHandler) {
+		h.secret = secret
+	}
+}
+
+// NewWebhookHandler constructs a WebhookHandler that refreshes store upon
+// receiving a push event targeting its configured ref.
+func NewWebhookHandler(store *SnapshotStore, opts ...containers.Option[WebhookHandler]) *WebhookHandler {
+	h := &WebhookHandler{
+		logger: store.logger,
+		store:  store,
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	if len(h.secret) == 0 {
+		h.logger.Warn("git webhook handler has no secret configured; requests will not be authenticated and any caller able to reach this endpoint can trigger a snapshot refresh (see WithWebhookSecret)")
+	}
+
+	return h
+}
+
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "reading request body", http.StatusBadRequest)
+		return
+	}
+
+	ref, ignored, err := h.parsePush(r, body)
+	if err != nil {
+		h.logger.Warn("rejecting git webhook", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if ignored {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if ref != "" && ref != plumbingRefName(h.store.ref) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if err := h.refresh(r.Context()); err != nil {
+		h.logger.Error("refreshing snapshot from webhook", zap.Error(err))
+		http.Error(w, "refreshing snapshot", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// refresh coalesces concurrent webhook deliveries into a single in-flight
+// SnapshotStore.Refresh call, so that a burst of pushes never has more than
+// one fetch in flight at a time. A delivery that's coalesced into an already-running refresh
+// still needs its commit to be observed, so every caller marks the run
+// dirty before joining it; once the in-flight refresh returns, a caller
+// that finds it's still dirty (a push landed after the fetch had already
+// started) triggers one more refresh rather than returning a possibly
+// stale result. This guarantees the final state is always fetched even
+// with polling disabled.
+func (h *WebhookHandler) refresh(ctx context.Context) error {
+	for {
+		h.mu.Lock()
+		h.dirty = true
+		h.mu.Unlock()
+
+		_, err, _ := h.group.Do("refresh", func() (any, error) {
+			h.mu.Lock()
+			h.dirty = false
+			h.mu.Unlock()
+			return nil, h.store.Refresh(ctx)
+		})
+		if err != nil {
+			return err
+		}
+
+		h.mu.Lock()
+		dirty := h.dirty
+		h.mu.Unlock()
+		if !dirty {
+			return nil
+		}
+	}
+}
+
+// parsePush authenticates and parses an incoming push event, returning the
+// ref it targeted. ignored is true when the event is of a kind this handler
+// doesn't act on (e.g. a ping event), which is not an error.
+func (h *WebhookHandler) parsePush(r *http.Request, body []byte) (ref string, ignored bool, err error) {
+	switch {
+	case r.Header.Get("X-GitHub-Event") != "":
+		if r.Header.Get("X-GitHub-Event") == "ping" {
+			return "", true, nil
+		}
+		if r.Header.Get("X-GitHub-Event") != "push" {
+			return "", true, nil
+		}
+		if err := h.verifyHMAC(r.Header.Get("X-Hub-Signature-256"), "sha256=", sha256.New, body); err != nil {
+			return "", false, err
+		}
+		return parseRefField(body)
+	case r.Header.Get("X-Gitlab-Event") != "":
+		if r.Header.Get("X-Gitlab-Event") != "Push Hook" {
+			return "", true, nil
+		}
+		if len(h.secret) > 0 {
+			if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Gitlab-Token")), h.secret) != 1 {
+				return "", false, errors.New("git: invalid gitlab webhook token")
+			}
+		}
+		return parseRefField(body)
+	case r.Header.Get("X-Gitea-Event") != "":
+		if r.Header.Get("X-Gitea-Event") != "push" {
+			return "", true, nil
+		}
+		if err := h.verifyHMAC(r.Header.Get("X-Gitea-Signature"), "", sha256.New, body); err != nil {
+			return "", false, err
+		}
+		return parseRefField(body)
+	case r.Header.Get("X-Event-Key") != "":
+		if r.Header.Get("X-Event-Key") != "repo:push" {
+			return "", true, nil
+		}
+		// Bitbucket Server/Data Center signs payloads with an HMAC-SHA256
+		// when a webhook secret has been configured in the repository
+		// settings (Bitbucket Cloud does not sign payloads at all, so this
+		// is a no-op unless WithWebhookSecret is configured).
+		if err := h.verifyHMAC(r.Header.Get("X-Hub-Signature"), "sha256=", sha256.New, body); err != nil {
+			return "", false, err
+		}
+		return parseBitbucketRef(body)
+	default:
+		return "", false, errors.New("git: unrecognized webhook provider")
+	}
+}
+
+func (h *WebhookHandler) verifyHMAC(signature, prefix string, hashNew func() hash.Hash, body []byte) error {
+	if len(h.secret) == 0 {
+		return nil
+	}
+
+	signature = strings.TrimPrefix(signature, prefix)
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("git: decoding webhook signature: %w", err)
+	}
+
+	mac := hmac.New(hashNew, h.secret)
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return errors.New("git: webhook signature mismatch")
+	}
+
+	return nil
+}
+
+func parseRefField(body []byte) (string, bool, error) {
+	var payload struct {
+		Ref string `json:"ref"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return "", false, fmt.Errorf("git: parsing webhook payload: %w", err)
+	}
+
+	return payload.Ref, false, nil
+}
+
+func parseBitbucketRef(body []byte) (string, bool, error) {
+	var payload struct {
+		Push struct {
+			Changes []struct {
+				New struct {
+					Type string `json:"type"`
+					Name string `json:"name"`
+				} `json:"new"`
+			} `json:"changes"`
+		} `json:"push"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return "", false, fmt.Errorf("git: parsing webhook payload: %w", err)
+	}
+
+	if len(payload.Push.Changes) == 0 {
+		return "", true, nil
+	}
+
+	change := payload.Push.Changes[0]
+	if change.New.Type != "branch" {
+		return "", true, nil
+	}
+
+	return plumbingRefName(change.New.Name), false, nil
+}
+
+func plumbingRefName(ref string) string {
+	if strings.HasPrefix(ref, "refs/") {
+		return ref
+	}
+	return "refs/heads/" + ref
+}